@@ -20,6 +20,7 @@ import (
 	"android/soong/genrule"
 	"android/soong/java"
 	"android/soong/phony"
+	"android/soong/rust"
 
 	"fmt"
 	"io"
@@ -41,6 +42,7 @@ var (
 	langJava            = "java"
 	langNdk             = "ndk"
 	langNdkPlatform     = "ndk_platform"
+	langRust            = "rust"
 
 	pctx = android.NewPackageContext("android/aidl")
 
@@ -69,6 +71,20 @@ var (
 		Description: "AIDL Java ${in}",
 	}, "imports", "outDir", "optionalFlags")
 
+	aidlRustRule = pctx.StaticRule("aidlRustRule", blueprint.RuleParams{
+		// lib.rs (the declared ${out}) is generated directly inside ${outDir},
+		// alongside the submodule .rs files aidl emits for the rest of the
+		// package hierarchy, so rustc's `mod` resolution finds them relative
+		// to lib.rs without any extra copying step.
+		Command: `rm -rf "${outDir}" && mkdir -p "${outDir}" && ` +
+			`${aidlCmd} --lang=rust ${optionalFlags} --structured --ninja -d ${out}.d ` +
+			`-o ${outDir} ${imports} ${in}`,
+		Depfile:     "${out}.d",
+		Deps:        blueprint.DepsGCC,
+		CommandDeps: []string{"${aidlCmd}"},
+		Description: "AIDL Rust ${in}",
+	}, "imports", "outDir", "optionalFlags")
+
 	aidlDumpApiRule = pctx.StaticRule("aidlDumpApiRule", blueprint.RuleParams{
 		Command: `rm -rf "${outDir}" && mkdir -p "${outDir}" && ` +
 			`${aidlCmd} --dumpapi --structured ${imports} --out ${outDir} ${in} && ` +
@@ -94,6 +110,20 @@ var (
 			CommandDeps: []string{"${bpmodifyCmd}"},
 		}, "to", "name", "version", "bp", "apiDir", "apiPreamble")
 
+	// Regenerates the dump of an already-frozen version in place, without
+	// touching the versions: list in Android.bp. Used by <name>-update-api
+	// when the interface owner wants to amend the latest frozen snapshot
+	// (e.g. to pick up a preamble/license change) instead of freezing a new
+	// version.
+	aidlUpdateApiRule = pctx.AndroidStaticRule("aidlUpdateApiRule",
+		blueprint.RuleParams{
+			Command: `mkdir -p ${to} && rm -rf ${to}/* && ` +
+				`cp -rf ${apiDir}/. ${to} && ` +
+				`find ${to} -type f -exec bash -c ` +
+				`"cat ${apiPreamble} {} > {}.temp; mv {}.temp {}" \; && ` +
+				`touch ${out}`,
+		}, "to", "apiDir", "apiPreamble")
+
 	aidlCheckApiRule = pctx.StaticRule("aidlCheckApiRule", blueprint.RuleParams{
 		Command: `(${aidlCmd} --checkapi ${old} ${new} && touch ${out}) || ` +
 			`(cat ${messageFile} && exit 1)`,
@@ -113,8 +143,11 @@ func init() {
 	pctx.HostBinToolVariable("bpmodifyCmd", "bpmodify")
 	pctx.SourcePathVariable("aidlToJniCmd", "system/tools/aidl/build/aidl_to_jni.py")
 	android.RegisterModuleType("aidl_interface", aidlInterfaceFactory)
+	android.RegisterModuleType("aidl_interface_headers", aidlInterfaceHeadersFactory)
+	android.RegisterModuleType("aidl_rust_source_provider", aidlRustSourceProviderFactory)
 	android.RegisterModuleType("aidl_mapping", aidlMappingFactory)
 	android.RegisterMakeVarsProvider(pctx, allAidlInterfacesMakeVars)
+	android.RegisterSingletonType("aidl_mappings", aidlMappingsSingletonFactory)
 }
 
 // wrap(p, a, s) = [p + v + s for v in a]
@@ -164,15 +197,80 @@ func isRelativePath(path string) bool {
 		!strings.HasPrefix(path, "../") && !strings.HasPrefix(path, "/")
 }
 
+// aidlInterfaceHeaderProvider is implemented by aidl_interface_headers so
+// that aidlGenRule and aidlApi can pull in its sources and import path
+// without relying on the whole source tree being readable.
+type aidlInterfaceHeaderProvider interface {
+	android.Module
+	HeaderSrcs() android.Paths
+	HeaderImportPath() string
+}
+
+type aidlInterfaceHeadersProperties struct {
+	// List of .aidl files that make up this set of headers.
+	Srcs []string `android:"path"`
+	// Relative path for imports. By default assumes AIDL path is relative to current directory.
+	Local_include_dir string
+}
+
+// aidl_interface_headers wraps a set of .aidl files that other aidl_interface
+// modules can import from, without needing the entire source tree to be
+// visible to the AIDL compiler. This is the sandbox-safe replacement for the
+// untracked include_dirs mechanism.
+type aidlInterfaceHeaders struct {
+	android.ModuleBase
+
+	properties aidlInterfaceHeadersProperties
+
+	fullImportPath string
+	srcs           android.Paths
+}
+
+func (h *aidlInterfaceHeaders) DepsMutator(ctx android.BottomUpMutatorContext) {
+}
+
+func (h *aidlInterfaceHeaders) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	if !isRelativePath(h.properties.Local_include_dir) {
+		ctx.PropertyErrorf("local_include_dir", "must be relative path: "+h.properties.Local_include_dir)
+		return
+	}
+	h.srcs = android.PathsForModuleSrc(ctx, h.properties.Srcs)
+	h.fullImportPath = filepath.Join(ctx.ModuleDir(), h.properties.Local_include_dir)
+}
+
+func (h *aidlInterfaceHeaders) HeaderSrcs() android.Paths {
+	return h.srcs
+}
+
+func (h *aidlInterfaceHeaders) HeaderImportPath() string {
+	return h.fullImportPath
+}
+
+func aidlInterfaceHeadersFactory() android.Module {
+	h := &aidlInterfaceHeaders{}
+	h.AddProperties(&h.properties)
+	android.InitAndroidModule(h)
+	return h
+}
+
 type aidlGenProperties struct {
 	Srcs      []string `android:"path"`
 	AidlRoot  string   // base directory for the input aidl file
 	Imports   []string
+	Headers   []string
 	Stability *string
 	Lang      string // target language [java|cpp|ndk]
 	BaseName  string
 	GenLog    bool
 	Version   string
+
+	// Set when this module is generating sources for an already-frozen
+	// version of BaseName (as opposed to its current, still-evolving ToT
+	// sources). When true, imports of other aidl_interfaces are resolved
+	// against each import's own "current" API dump instead of its live
+	// sources, so this frozen version keeps compiling against the shape it
+	// was actually frozen with even as the import evolves underneath it.
+	Use_frozen_imports bool
 }
 
 type aidlGenRule struct {
@@ -189,6 +287,20 @@ type aidlGenRule struct {
 	genOutputs    android.WritablePaths
 }
 
+// genSubDir returns a stable, human-readable name for this module's
+// generated-source subdirectory, e.g. "foo-iface-cpp" or
+// "foo-iface-cpp-V1". Rooting the generated tree at this name (rather than
+// directly at the module's own gen dir) keeps include paths IDE-friendly
+// and unchanging as unrelated modules are added to or removed from the
+// build graph.
+func (g *aidlGenRule) genSubDir() string {
+	name := g.properties.BaseName + "-" + g.properties.Lang
+	if g.properties.Version != "" {
+		name += "-V" + g.properties.Version
+	}
+	return name
+}
+
 var _ android.SourceFileProducer = (*aidlGenRule)(nil)
 var _ genrule.SourceFileGenerator = (*aidlGenRule)(nil)
 
@@ -199,26 +311,42 @@ func (g *aidlGenRule) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		return
 	}
 
-	genDirTimestamp := android.PathForModuleGen(ctx, "timestamp")
+	genDirTimestamp := android.PathForModuleGen(ctx, g.genSubDir(), "timestamp")
 	g.implicitInputs = append(g.implicitInputs, genDirTimestamp)
 
 	var importPaths []string
 	importPaths = append(importPaths, imports...)
 	ctx.VisitDirectDeps(func(dep android.Module) {
 		if importedAidl, ok := dep.(*aidlInterface); ok {
-			importPaths = append(importPaths, importedAidl.properties.Full_import_paths...)
+			// When building an already-frozen version of ourselves, imports
+			// (other than ourselves) are resolved below against the
+			// imported interface's own "current" API dump instead, so that
+			// this frozen version keeps compiling against the shape it was
+			// actually frozen with even as the import evolves.
+			if !g.properties.Use_frozen_imports || importedAidl.ModuleBase.Name() == g.properties.BaseName {
+				importPaths = append(importPaths, importedAidl.properties.Full_import_paths...)
+			}
 		} else if api, ok := dep.(*aidlApi); ok {
-			// When compiling an AIDL interface, also make sure that each
-			// version of the interface is compatible with its previous version
-			for _, path := range api.checkApiTimestamps {
-				g.implicitInputs = append(g.implicitInputs, path)
+			if api.properties.BaseName == g.properties.BaseName {
+				// When compiling an AIDL interface, also make sure that each
+				// version of the interface is compatible with its previous version
+				for _, path := range api.checkApiTimestamps {
+					g.implicitInputs = append(g.implicitInputs, path)
+				}
+			} else if g.properties.Use_frozen_imports {
+				if dir, ok := api.apiDumpDirs["current"]; ok {
+					importPaths = append(importPaths, dir.String())
+				}
 			}
+		} else if headers, ok := dep.(aidlInterfaceHeaderProvider); ok {
+			importPaths = append(importPaths, headers.HeaderImportPath())
+			g.implicitInputs = append(g.implicitInputs, headers.HeaderSrcs()...)
 		}
 	})
 	g.importFlags = strings.Join(wrap("-I", importPaths, ""), " ")
 
-	g.genOutDir = android.PathForModuleGen(ctx)
-	g.genHeaderDir = android.PathForModuleGen(ctx, "include")
+	g.genOutDir = android.PathForModuleGen(ctx, g.genSubDir())
+	g.genHeaderDir = android.PathForModuleGen(ctx, g.genSubDir(), "include")
 	for _, src := range srcs {
 		outFile, headers := g.generateBuildActionsForSingleAidl(ctx, src)
 		g.genOutputs = append(g.genOutputs, outFile)
@@ -257,7 +385,7 @@ func (g *aidlGenRule) generateBuildActionsForSingleAidl(ctx android.ModuleContex
 	}
 	relPath, _ := filepath.Rel(baseDir, src.String())
 	relPath = pathtools.ReplaceExtension(relPath, ext)
-	outFile := android.PathForModuleGen(ctx, relPath)
+	outFile := android.PathForModuleGen(ctx, g.genSubDir(), relPath)
 
 	var optionalFlags []string
 	if g.properties.Version != "" {
@@ -351,7 +479,11 @@ func (g *aidlGenRule) GeneratedHeaderDirs() android.Paths {
 
 func (g *aidlGenRule) DepsMutator(ctx android.BottomUpMutatorContext) {
 	ctx.AddDependency(ctx.Module(), nil, wrap("", g.properties.Imports, aidlInterfaceSuffix)...)
-	ctx.AddDependency(ctx.Module(), nil, g.properties.BaseName+aidlApiSuffix)
+	// Depend on every import's aidlApi module, not just our own, so that a
+	// snapshot build (Use_frozen_imports) can resolve -I flags against each
+	// import's "current" API dump instead of its live sources.
+	ctx.AddDependency(ctx.Module(), nil, wrap("", g.properties.Imports, aidlApiSuffix)...)
+	ctx.AddDependency(ctx.Module(), nil, g.properties.Headers...)
 }
 
 func aidlGenFactory() android.Module {
@@ -361,11 +493,121 @@ func aidlGenFactory() android.Module {
 	return g
 }
 
+// aidlRustSourceProviderRule generates a single Rust crate (lib.rs plus
+// submodules mirroring the AIDL package hierarchy) from an interface's
+// entire set of .aidl sources in one invocation, unlike aidlGenRule which
+// generates one output file per input .aidl file. It implements Soong's
+// rust.SourceProvider so that it can be consumed as the Srcs of a
+// rust_library module.
+type aidlRustSourceProviderRule struct {
+	android.ModuleBase
+	rust.BaseSourceProvider
+
+	properties aidlGenProperties
+
+	implicitInputs android.Paths
+	importFlags    string
+}
+
+func (g *aidlRustSourceProviderRule) GenerateSource(ctx android.ModuleContext, deps rust.PathDeps) android.Path {
+	srcs, imports := getPaths(ctx, g.properties.Srcs)
+
+	if ctx.Failed() {
+		return nil
+	}
+
+	var importPaths []string
+	importPaths = append(importPaths, imports...)
+	ctx.VisitDirectDeps(func(dep android.Module) {
+		if importedAidl, ok := dep.(*aidlInterface); ok {
+			// When building an already-frozen version of ourselves, imports
+			// (other than ourselves) are resolved below against the
+			// imported interface's own "current" API dump instead, so that
+			// this frozen version keeps compiling against the shape it was
+			// actually frozen with even as the import evolves.
+			if !g.properties.Use_frozen_imports || importedAidl.ModuleBase.Name() == g.properties.BaseName {
+				importPaths = append(importPaths, importedAidl.properties.Full_import_paths...)
+			}
+		} else if api, ok := dep.(*aidlApi); ok {
+			if api.properties.BaseName == g.properties.BaseName {
+				// When compiling an AIDL interface, also make sure that each
+				// version of the interface is compatible with its previous version
+				for _, path := range api.checkApiTimestamps {
+					g.implicitInputs = append(g.implicitInputs, path)
+				}
+			} else if g.properties.Use_frozen_imports {
+				if dir, ok := api.apiDumpDirs["current"]; ok {
+					importPaths = append(importPaths, dir.String())
+				}
+			}
+		} else if headers, ok := dep.(aidlInterfaceHeaderProvider); ok {
+			importPaths = append(importPaths, headers.HeaderImportPath())
+			g.implicitInputs = append(g.implicitInputs, headers.HeaderSrcs()...)
+		}
+	})
+	g.importFlags = strings.Join(wrap("-I", importPaths, ""), " ")
+
+	// lib.rs and the submodule .rs files aidl generates alongside it (mirroring
+	// the AIDL package hierarchy) must live in the same directory: rustc
+	// resolves lib.rs's `mod` declarations relative to lib.rs's own location,
+	// not to some other intermediate directory.
+	outDir := android.PathForModuleOut(ctx, "out")
+	libRs := outDir.Join(ctx, "lib.rs")
+
+	var optionalFlags []string
+	if g.properties.Version != "" {
+		optionalFlags = append(optionalFlags, "--version "+g.properties.Version)
+	}
+	if g.properties.Stability != nil {
+		optionalFlags = append(optionalFlags, "--stability", *g.properties.Stability)
+	}
+
+	ctx.ModuleBuild(pctx, android.ModuleBuildParams{
+		Rule:      aidlRustRule,
+		Inputs:    srcs,
+		Implicits: g.implicitInputs,
+		Output:    libRs,
+		Args: map[string]string{
+			"imports":       g.importFlags,
+			"outDir":        outDir.String(),
+			"optionalFlags": strings.Join(optionalFlags, " "),
+		},
+	})
+
+	return libRs
+}
+
+func (g *aidlRustSourceProviderRule) DepsMutator(ctx android.BottomUpMutatorContext) {
+	g.BaseSourceProvider.DepsMutator(ctx)
+	ctx.AddDependency(ctx.Module(), nil, wrap("", g.properties.Imports, aidlInterfaceSuffix)...)
+	// Depend on every import's aidlApi module, not just our own, so that a
+	// snapshot build (Use_frozen_imports) can resolve -I flags against each
+	// import's "current" API dump instead of its live sources.
+	ctx.AddDependency(ctx.Module(), nil, wrap("", g.properties.Imports, aidlApiSuffix)...)
+	ctx.AddDependency(ctx.Module(), nil, g.properties.Headers...)
+}
+
+func aidlRustSourceProviderFactory() android.Module {
+	g := &aidlRustSourceProviderRule{}
+	g.AddProperties(&g.properties)
+	return rust.NewSourceProvider(g, &g.BaseSourceProvider)
+}
+
 type aidlApiProperties struct {
 	BaseName string
 	Srcs     []string `android:"path"`
 	Imports  []string
+	Headers  []string
 	Versions []string
+
+	// When set to true, the current version of this interface is considered
+	// frozen and ToT sources must match the latest frozen dump exactly - no
+	// backwards-compatible evolution is allowed until this is set back to
+	// false. When explicitly set to false, the opposite is enforced: ToT is
+	// only ever checked for backwards compatibility with the latest version,
+	// regardless of DefaultAppTargetSdkInt. Defaults to following
+	// DefaultAppTargetSdkInt when unset.
+	Frozen *bool
 }
 
 type aidlApi struct {
@@ -378,12 +620,46 @@ type aidlApi struct {
 
 	// for triggering freezing API as the new version
 	freezeApiTimestamp android.WritablePath
+
+	// for triggering an in-place regeneration of the latest existing version
+	updateApiTimestamp android.WritablePath
+
+	// apiDumpDirs maps each frozen version (plus the pseudo-version "current"
+	// for the ToT dump) to the directory holding its AIDL API dump. Consumed
+	// by aidlGenRule so that a snapshot build of an importing interface
+	// resolves its imports against the imported interface's own "current"
+	// dump rather than against whatever its live sources look like today.
+	apiDumpDirs map[string]android.Path
 }
 
 func (m *aidlApi) apiDir() string {
 	return filepath.Join(aidlApiDir, m.properties.BaseName)
 }
 
+func (m *aidlApi) hasVersion() bool {
+	return len(m.properties.Versions) > 0
+}
+
+func (m *aidlApi) latestVersion() string {
+	if !m.hasVersion() {
+		return ""
+	}
+	return m.properties.Versions[len(m.properties.Versions)-1]
+}
+
+// isFrozen returns true when the interface owner has explicitly declared
+// that the current (latest) version must not change at all.
+func (m *aidlApi) isFrozen() bool {
+	return proptools.Bool(m.properties.Frozen) && m.hasVersion()
+}
+
+// isExplicitlyUnFrozen returns true when the interface owner has explicitly
+// declared that ToT may evolve (in a backwards-compatible way) regardless of
+// DefaultAppTargetSdkInt.
+func (m *aidlApi) isExplicitlyUnFrozen() bool {
+	return m.properties.Frozen != nil && !*m.properties.Frozen
+}
+
 // Version of the interface at ToT if it is frozen
 func (m *aidlApi) validateCurrentVersion(ctx android.ModuleContext) string {
 	if len(m.properties.Versions) == 0 {
@@ -409,10 +685,14 @@ func (m *aidlApi) createApiDumpFromSource(ctx android.ModuleContext) (apiDir and
 	}
 
 	var importPaths []string
+	var implicits android.Paths
 	importPaths = append(importPaths, imports...)
 	ctx.VisitDirectDeps(func(dep android.Module) {
 		if importedAidl, ok := dep.(*aidlInterface); ok {
 			importPaths = append(importPaths, importedAidl.properties.Full_import_paths...)
+		} else if headers, ok := dep.(aidlInterfaceHeaderProvider); ok {
+			importPaths = append(importPaths, headers.HeaderImportPath())
+			implicits = append(implicits, headers.HeaderSrcs()...)
 		}
 	})
 
@@ -426,9 +706,10 @@ func (m *aidlApi) createApiDumpFromSource(ctx android.ModuleContext) (apiDir and
 		latestVersion = m.properties.Versions[len(m.properties.Versions)-1]
 	}
 	ctx.ModuleBuild(pctx, android.ModuleBuildParams{
-		Rule:    aidlDumpApiRule,
-		Outputs: append(apiFiles, hashFile),
-		Inputs:  srcs,
+		Rule:      aidlDumpApiRule,
+		Outputs:   append(apiFiles, hashFile),
+		Inputs:    srcs,
+		Implicits: implicits,
 		Args: map[string]string{
 			"imports":       strings.Join(wrap("-I", importPaths, ""), " "),
 			"outDir":        apiDir.String(),
@@ -467,6 +748,31 @@ func (m *aidlApi) freezeApiDumpAsVersion(ctx android.ModuleContext, apiDumpDir a
 	return timestampFile
 }
 
+func (m *aidlApi) updateApiDumpAsVersion(ctx android.ModuleContext, apiDumpDir android.Path, apiFiles android.Paths, version string) android.WritablePath {
+	timestampFile := android.PathForModuleOut(ctx, "updateapi_"+version+".timestamp")
+
+	modulePath := android.PathForModuleSrc(ctx).String()
+
+	var implicits android.Paths
+	implicits = append(implicits, apiFiles...)
+
+	apiPreamble := android.PathForSource(ctx, "system/tools/aidl/build/api_preamble.txt")
+	implicits = append(implicits, apiPreamble)
+
+	ctx.ModuleBuild(pctx, android.ModuleBuildParams{
+		Rule:        aidlUpdateApiRule,
+		Description: "Updating AIDL API of " + m.properties.BaseName + " at version " + version,
+		Implicits:   implicits,
+		Output:      timestampFile,
+		Args: map[string]string{
+			"to":          filepath.Join(modulePath, m.apiDir(), version),
+			"apiDir":      apiDumpDir.String(),
+			"apiPreamble": apiPreamble.String(),
+		},
+	})
+	return timestampFile
+}
+
 func (m *aidlApi) checkCompatibility(ctx android.ModuleContext, oldApiDir android.Path, oldApiFiles android.Paths, newApiDir android.Path, newApiFiles android.Paths) android.WritablePath {
 	newVersion := newApiDir.Base()
 	timestampFile := android.PathForModuleOut(ctx, "checkapi_"+newVersion+".timestamp")
@@ -526,20 +832,41 @@ func (m *aidlApi) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 	m.freezeApiTimestamp = m.freezeApiDumpAsVersion(ctx, currentDumpDir, currentApiFiles.Paths(), currentVersion)
 
+	var versions []string
 	apiDirs := make(map[string]android.Path)
 	apiFiles := make(map[string]android.Paths)
 	for _, ver := range m.properties.Versions {
-		apiDir := android.PathForModuleSrc(ctx, m.apiDir(), ver)
+		apiDirPath := filepath.Join(m.apiDir(), ver)
+		if !android.ExistentPathForSource(ctx, apiDirPath).Valid() {
+			if ctx.Config().AllowMissingDependencies() {
+				// This can happen in an unbundled / single-module build where the
+				// module providing the frozen API dump isn't checked out. Record it
+				// as a missing dependency instead of failing analysis now; the
+				// module will only fail if it is actually built.
+				ctx.AddMissingDependencies([]string{apiDirPath})
+				continue
+			}
+			ctx.ModuleErrorf("%s does not exist. Has it been frozen with `m %s-freeze-api`?", apiDirPath, m.properties.BaseName)
+			continue
+		}
+		versions = append(versions, ver)
+		apiDir := android.PathForModuleSrc(ctx, apiDirPath)
 		apiDirs[ver] = apiDir
 		apiFiles[ver] = ctx.Glob(filepath.Join(apiDir.String(), "**/*.aidl"), nil)
 	}
 	apiDirs[currentVersion] = currentDumpDir
 	apiFiles[currentVersion] = currentApiFiles.Paths()
 
+	m.apiDumpDirs = make(map[string]android.Path, len(apiDirs)+1)
+	for ver, dir := range apiDirs {
+		m.apiDumpDirs[ver] = dir
+	}
+	m.apiDumpDirs["current"] = currentDumpDir
+
 	// Check that version X is backward compatible with version X-1
-	for i, newVersion := range m.properties.Versions {
+	for i, newVersion := range versions {
 		if i != 0 {
-			oldVersion := m.properties.Versions[i-1]
+			oldVersion := versions[i-1]
 			checkApiTimestamp := m.checkCompatibility(ctx, apiDirs[oldVersion], apiFiles[oldVersion], apiDirs[newVersion], apiFiles[newVersion])
 			m.checkApiTimestamps = append(m.checkApiTimestamps, checkApiTimestamp)
 		}
@@ -547,10 +874,14 @@ func (m *aidlApi) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 	// ... and that the currentVersion (ToT) is backwards compatible with or
 	// equal to the latest frozen version
-	if len(m.properties.Versions) >= 1 {
-		latestVersion := m.properties.Versions[len(m.properties.Versions)-1]
+	if len(versions) >= 1 {
+		latestVersion := versions[len(versions)-1]
 		var checkApiTimestamp android.WritablePath
-		if ctx.Config().DefaultAppTargetSdkInt() != android.FutureApiLevel {
+		if m.isExplicitlyUnFrozen() {
+			// The interface owner has explicitly opted into allowing
+			// backwards-compatible evolution, regardless of the target SDK.
+			checkApiTimestamp = m.checkCompatibility(ctx, apiDirs[latestVersion], apiFiles[latestVersion], apiDirs[currentVersion], apiFiles[currentVersion])
+		} else if m.isFrozen() || ctx.Config().DefaultAppTargetSdkInt() != android.FutureApiLevel {
 			// If API is frozen, don't allow any change to the API
 			latestHashFile := android.OptionalPathForModuleSrc(ctx, proptools.StringPtr(filepath.Join(m.apiDir(), latestVersion, ".hash")))
 			checkApiTimestamp = m.checkEquality(ctx, apiDirs[latestVersion], apiFiles[latestVersion], latestHashFile,
@@ -560,6 +891,8 @@ func (m *aidlApi) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 			checkApiTimestamp = m.checkCompatibility(ctx, apiDirs[latestVersion], apiFiles[latestVersion], apiDirs[currentVersion], apiFiles[currentVersion])
 		}
 		m.checkApiTimestamps = append(m.checkApiTimestamps, checkApiTimestamp)
+
+		m.updateApiTimestamp = m.updateApiDumpAsVersion(ctx, currentDumpDir, currentApiFiles.Paths(), latestVersion)
 	}
 }
 
@@ -567,15 +900,36 @@ func (m *aidlApi) AndroidMk() android.AndroidMkData {
 	return android.AndroidMkData{
 		Custom: func(w io.Writer, name, prefix, moduleDir string, data android.AndroidMkData) {
 			android.WriteAndroidMkData(w, data)
-			targetName := m.properties.BaseName + "-freeze-api"
-			fmt.Fprintln(w, ".PHONY:", targetName)
-			fmt.Fprintln(w, targetName+":", m.freezeApiTimestamp.String())
+
+			freezeApiTargetName := m.properties.BaseName + "-freeze-api"
+			fmt.Fprintln(w, ".PHONY:", freezeApiTargetName)
+			if m.isFrozen() {
+				// Freezing a new version on top of an explicitly frozen
+				// interface would silently make the "frozen" version mutable
+				// again, so refuse instead of freezing a new version.
+				fmt.Fprintln(w, freezeApiTargetName+":")
+				fmt.Fprintln(w, "\t$(error "+m.properties.BaseName+" is explicitly frozen. "+
+					"Set `frozen: false` in its aidl_interface definition before running `m "+freezeApiTargetName+"`.)")
+			} else {
+				fmt.Fprintln(w, freezeApiTargetName+":", m.freezeApiTimestamp.String())
+			}
+
+			updateApiTargetName := m.properties.BaseName + "-update-api"
+			fmt.Fprintln(w, ".PHONY:", updateApiTargetName)
+			if m.updateApiTimestamp != nil {
+				fmt.Fprintln(w, updateApiTargetName+":", m.updateApiTimestamp.String())
+			} else {
+				fmt.Fprintln(w, updateApiTargetName+":")
+				fmt.Fprintln(w, "\t$(error "+m.properties.BaseName+" has no frozen versions to update. "+
+					"Run `m "+freezeApiTargetName+"` to freeze the first version.)")
+			}
 		},
 	}
 }
 
 func (m *aidlApi) DepsMutator(ctx android.BottomUpMutatorContext) {
 	ctx.AddDependency(ctx.Module(), nil, wrap("", m.properties.Imports, aidlInterfaceSuffix)...)
+	ctx.AddDependency(ctx.Module(), nil, m.properties.Headers...)
 }
 
 func aidlApiFactory() android.Module {
@@ -595,9 +949,6 @@ type aidlInterfaceProperties struct {
 	// Whether the library can be used on host
 	Host_supported *bool
 
-	// Top level directories for includes.
-	// TODO(b/128940869): remove it if aidl_interface can depend on framework.aidl
-	Include_dirs []string
 	// Relative path for includes. By default assumes AIDL path is relative to current directory.
 	// TODO(b/111117220): automatically compute by letting AIDL parse multiple files simultaneously
 	Local_include_dir string
@@ -609,6 +960,10 @@ type aidlInterfaceProperties struct {
 	// interface or parcelable from another aidl_interface, you should put its name here.
 	Imports []string
 
+	// List of aidl_interface_headers modules that provide additional .aidl sources and import
+	// paths, without requiring the entire source tree to be visible to the AIDL compiler.
+	Headers []string
+
 	// Used by gen dependency to fill out aidl include path
 	Full_import_paths []string `blueprint:"mutated"`
 
@@ -624,6 +979,23 @@ type aidlInterfaceProperties struct {
 	// the list is considered as the most recent version.
 	Versions []string
 
+	// When set to true, ToT sources of this interface must match its latest
+	// frozen version exactly - no further evolution, not even in a
+	// backwards-compatible way, is allowed until this is set back to false.
+	// When explicitly set to false, ToT is only ever checked for backwards
+	// compatibility with the latest version, regardless of
+	// DefaultAppTargetSdkInt. Defaults to following DefaultAppTargetSdkInt
+	// when unset.
+	Frozen *bool
+
+	// List of APEXes that this module can be included in. This is copied onto
+	// every backend module generated for this interface, unless a backend
+	// overrides it with its own apex_available.
+	Apex_available []string
+
+	// Minimum sdk version that the generated backend libraries should support.
+	Min_sdk_version *string
+
 	Backend struct {
 		Java struct {
 			// Whether to generate Java code using Java binder APIs
@@ -632,6 +1004,14 @@ type aidlInterfaceProperties struct {
 			// Set to the version of the sdk to compile against
 			// Default: system_current
 			Sdk_version *string
+			// Whether to compile against the platform APIs instead of an
+			// SDK. Mutually exclusive in intent with Sdk_version, and
+			// required for a Java backend to be paired with `stability:
+			// "vintf"`.
+			// Default: false
+			Platform_apis *bool
+			// Overrides the top level apex_available for the Java backend
+			Apex_available []string
 		}
 		Cpp struct {
 			// Whether to generate C++ code using C++ binder APIs
@@ -641,6 +1021,12 @@ type aidlInterfaceProperties struct {
 			// about the transactions
 			// Default: false
 			Gen_log *bool
+			// Overrides the top level apex_available for the C++ backend
+			Apex_available []string
+			// Whether to build the additional native_bridge variant of the
+			// C++ generated library
+			// Default: false
+			Native_bridge_supported *bool
 		}
 		Ndk struct {
 			// Whether to generate C++ code using NDK binder APIs
@@ -650,6 +1036,24 @@ type aidlInterfaceProperties struct {
 			// about the transactions
 			// Default: false
 			Gen_log *bool
+			// Overrides the top level apex_available for the NDK backend
+			Apex_available []string
+			// Whether to build the additional native_bridge variant of the
+			// NDK generated library
+			// Default: false
+			Native_bridge_supported *bool
+		}
+		Rust struct {
+			// Whether to generate Rust code using Rust binder APIs
+			// Default: false
+			Enabled *bool
+			// List of APEXes that this module can be included in
+			Apex_available []string
+			// Minimum sdk version that the Rust variants of this interface can be used
+			Min_sdk_version *string
+			// The Rust edition to compile the generated crate with
+			// Default: "2018"
+			Edition *string
 		}
 	}
 }
@@ -675,6 +1079,12 @@ func (i *aidlInterface) shouldGenerateNdkBackend() bool {
 	return i.properties.Backend.Ndk.Enabled == nil || *i.properties.Backend.Ndk.Enabled
 }
 
+func (i *aidlInterface) shouldGenerateRustBackend() bool {
+	// explicitly false by default since Rust is a new backend and most
+	// interfaces haven't opted in yet
+	return proptools.Bool(i.properties.Backend.Rust.Enabled)
+}
+
 func (i *aidlInterface) checkImports(mctx android.LoadHookContext) {
 	for _, anImport := range i.properties.Imports {
 		other := lookupInterface(anImport)
@@ -697,6 +1107,11 @@ func (i *aidlInterface) checkImports(mctx android.LoadHookContext) {
 			mctx.PropertyErrorf("backend.ndk.enabled",
 				"NDK backend not enabled in the imported AIDL interface %q", anImport)
 		}
+
+		if i.shouldGenerateRustBackend() && !other.shouldGenerateRustBackend() {
+			mctx.PropertyErrorf("backend.rust.enabled",
+				"Rust backend not enabled in the imported AIDL interface %q", anImport)
+		}
 	}
 }
 
@@ -705,7 +1120,7 @@ func (i *aidlInterface) checkStability(mctx android.LoadHookContext) {
 		return
 	}
 
-	if i.shouldGenerateJavaBackend() {
+	if i.shouldGenerateJavaBackend() && !proptools.Bool(i.properties.Backend.Java.Platform_apis) {
 		mctx.PropertyErrorf("stability", "Java backend does not yet support stability.")
 	}
 
@@ -805,11 +1220,7 @@ func aidlInterfaceHook(mctx android.LoadHookContext, i *aidlInterface) {
 	if !isRelativePath(i.properties.Local_include_dir) {
 		mctx.PropertyErrorf("local_include_dir", "must be relative path: "+i.properties.Local_include_dir)
 	}
-	var importPaths []string
-	importPaths = append(importPaths, filepath.Join(mctx.ModuleDir(), i.properties.Local_include_dir))
-	importPaths = append(importPaths, i.properties.Include_dirs...)
-
-	i.properties.Full_import_paths = importPaths
+	i.properties.Full_import_paths = []string{filepath.Join(mctx.ModuleDir(), i.properties.Local_include_dir)}
 
 	i.checkImports(mctx)
 	i.checkStability(mctx)
@@ -830,38 +1241,46 @@ func aidlInterfaceHook(mctx android.LoadHookContext, i *aidlInterface) {
 		versionsForCpp[len(i.properties.Versions)-1] = ""
 	}
 	if i.shouldGenerateCppBackend() {
-		libs = append(libs, addCppLibrary(mctx, i, currentVersion, langCpp))
+		libs = append(libs, addLibrary(mctx, i, currentVersion, langCpp))
 		for _, version := range versionsForCpp {
-			addCppLibrary(mctx, i, version, langCpp)
+			addLibrary(mctx, i, version, langCpp)
 		}
 	}
 
 	if i.shouldGenerateNdkBackend() {
 		// TODO(b/119771576): inherit properties and export 'is vendor' computation from cc.go
 		if !proptools.Bool(i.properties.Vendor_available) {
-			libs = append(libs, addCppLibrary(mctx, i, currentVersion, langNdk))
+			libs = append(libs, addLibrary(mctx, i, currentVersion, langNdk))
 			for _, version := range versionsForCpp {
-				addCppLibrary(mctx, i, version, langNdk)
+				addLibrary(mctx, i, version, langNdk)
 			}
 		}
 		// TODO(b/121157555): combine with '-ndk' variant
-		libs = append(libs, addCppLibrary(mctx, i, currentVersion, langNdkPlatform))
+		libs = append(libs, addLibrary(mctx, i, currentVersion, langNdkPlatform))
+		for _, version := range versionsForCpp {
+			addLibrary(mctx, i, version, langNdkPlatform)
+		}
+	}
+	if i.shouldGenerateRustBackend() {
+		libs = append(libs, addLibrary(mctx, i, currentVersion, langRust))
 		for _, version := range versionsForCpp {
-			addCppLibrary(mctx, i, version, langNdkPlatform)
+			addLibrary(mctx, i, version, langRust)
 		}
 	}
+
 	versionsForJava := i.properties.Versions
 	if i.hasVersion() {
 		versionsForJava = append(i.properties.Versions, "")
 	}
 	if i.shouldGenerateJavaBackend() {
-		libs = append(libs, addJavaLibrary(mctx, i, currentVersion))
+		libs = append(libs, addLibrary(mctx, i, currentVersion, langJava))
 		for _, version := range versionsForJava {
-			addJavaLibrary(mctx, i, version)
+			addLibrary(mctx, i, version, langJava)
 		}
 	}
 
 	addApiModule(mctx, i)
+	addMapping(mctx, i)
 
 	// Reserve this module name for future use
 	mctx.CreateModule(phony.PhonyFactory, &phonyProperties{
@@ -870,10 +1289,25 @@ func aidlInterfaceHook(mctx android.LoadHookContext, i *aidlInterface) {
 	})
 }
 
+// addLibrary dispatches to the backend-specific library generator for lang,
+// so that callers in aidlInterfaceHook don't need to know which generator
+// function backs which target language.
+func addLibrary(mctx android.LoadHookContext, i *aidlInterface, version string, lang string) string {
+	switch lang {
+	case langJava:
+		return addJavaLibrary(mctx, i, version)
+	case langRust:
+		return addRustLibrary(mctx, i, version)
+	default:
+		return addCppLibrary(mctx, i, version, lang)
+	}
+}
+
 func addCppLibrary(mctx android.LoadHookContext, i *aidlInterface, version string, lang string) string {
 	cppSourceGen := i.versionedName(mctx, version) + "-" + lang + "-source"
 	cppModuleGen := i.versionedName(mctx, version) + "-" + lang
 	cppOutputGen := i.cppOutputName(version) + "-" + lang
+	useFrozenImports := !i.isCurrentVersion(mctx, version)
 	if i.hasVersion() && version == "" {
 		version = i.latestVersion()
 	}
@@ -886,23 +1320,35 @@ func addCppLibrary(mctx android.LoadHookContext, i *aidlInterface, version strin
 	}
 
 	genLog := false
+	apexAvailable := i.properties.Apex_available
+	nativeBridgeSupported := false
 	if lang == langCpp {
 		genLog = proptools.Bool(i.properties.Backend.Cpp.Gen_log)
+		nativeBridgeSupported = proptools.Bool(i.properties.Backend.Cpp.Native_bridge_supported)
+		if len(i.properties.Backend.Cpp.Apex_available) > 0 {
+			apexAvailable = i.properties.Backend.Cpp.Apex_available
+		}
 	} else if lang == langNdk || lang == langNdkPlatform {
 		genLog = proptools.Bool(i.properties.Backend.Ndk.Gen_log)
+		nativeBridgeSupported = proptools.Bool(i.properties.Backend.Ndk.Native_bridge_supported)
+		if len(i.properties.Backend.Ndk.Apex_available) > 0 {
+			apexAvailable = i.properties.Backend.Ndk.Apex_available
+		}
 	}
 
 	mctx.CreateModule(aidlGenFactory, &nameProperties{
 		Name: proptools.StringPtr(cppSourceGen),
 	}, &aidlGenProperties{
-		Srcs:      srcs,
-		AidlRoot:  aidlRoot,
-		Imports:   concat(i.properties.Imports, []string{i.ModuleBase.Name()}),
-		Stability: i.properties.Stability,
-		Lang:      lang,
-		BaseName:  i.ModuleBase.Name(),
-		GenLog:    genLog,
-		Version:   version,
+		Srcs:               srcs,
+		AidlRoot:           aidlRoot,
+		Imports:            concat(i.properties.Imports, []string{i.ModuleBase.Name()}),
+		Headers:            i.properties.Headers,
+		Stability:          i.properties.Stability,
+		Lang:               lang,
+		BaseName:           i.ModuleBase.Name(),
+		GenLog:             genLog,
+		Version:            version,
+		Use_frozen_imports: useFrozenImports,
 	})
 
 	importExportDependencies := wrap("", i.properties.Imports, "-"+lang)
@@ -956,14 +1402,74 @@ func addCppLibrary(mctx android.LoadHookContext, i *aidlInterface, version strin
 		Cpp_std:                   cpp_std,
 		Cflags:                    append(addCflags, "-Wextra", "-Wall", "-Werror"),
 		Stem:                      proptools.StringPtr(cppOutputGen),
+		Apex_available:            apexAvailable,
+		Min_sdk_version:           i.properties.Min_sdk_version,
+		Native_bridge_supported:   proptools.BoolPtr(nativeBridgeSupported),
 	}, &i.properties.VndkProperties)
 
 	return cppModuleGen
 }
 
+func addRustLibrary(mctx android.LoadHookContext, i *aidlInterface, version string) string {
+	rustSourceGen := i.versionedName(mctx, version) + "-rust-source"
+	rustModuleGen := i.versionedName(mctx, version) + "-rust"
+	rustOutputGen := i.cppOutputName(version) + "-rust"
+	useFrozenImports := !i.isCurrentVersion(mctx, version)
+	if i.hasVersion() && version == "" {
+		version = i.latestVersion()
+	}
+	srcs, aidlRoot := i.srcsForVersion(mctx, version)
+	if len(srcs) == 0 {
+		// This can happen when the version is about to be frozen; the version
+		// directory is created but API dump hasn't been copied there.
+		// Don't create a library for the yet-to-be-frozen version.
+		return ""
+	}
+
+	mctx.CreateModule(aidlRustSourceProviderFactory, &nameProperties{
+		Name: proptools.StringPtr(rustSourceGen),
+	}, &aidlGenProperties{
+		Srcs:               srcs,
+		AidlRoot:           aidlRoot,
+		Imports:            concat(i.properties.Imports, []string{i.ModuleBase.Name()}),
+		Headers:            i.properties.Headers,
+		Stability:          i.properties.Stability,
+		Lang:               langRust,
+		BaseName:           i.ModuleBase.Name(),
+		Version:            version,
+		Use_frozen_imports: useFrozenImports,
+	})
+
+	importExportDependencies := wrap("", i.properties.Imports, "-rust")
+
+	apexAvailable := i.properties.Apex_available
+	if len(i.properties.Backend.Rust.Apex_available) > 0 {
+		apexAvailable = i.properties.Backend.Rust.Apex_available
+	}
+	minSdkVersion := i.properties.Min_sdk_version
+	if i.properties.Backend.Rust.Min_sdk_version != nil {
+		minSdkVersion = i.properties.Backend.Rust.Min_sdk_version
+	}
+
+	mctx.CreateModule(rust.LibraryFactory, &nameProperties{
+		Name: proptools.StringPtr(rustModuleGen),
+	}, &rustProperties{
+		Crate_name:      strings.ReplaceAll(i.ModuleBase.Name(), "-", "_"),
+		Srcs:            []string{":" + rustSourceGen},
+		Rustlibs:        importExportDependencies,
+		Apex_available:  apexAvailable,
+		Min_sdk_version: minSdkVersion,
+		Edition:         i.properties.Backend.Rust.Edition,
+		Stem:            proptools.StringPtr(rustOutputGen),
+	})
+
+	return rustModuleGen
+}
+
 func addJavaLibrary(mctx android.LoadHookContext, i *aidlInterface, version string) string {
 	javaSourceGen := i.versionedName(mctx, version) + "-java-source"
 	javaModuleGen := i.versionedName(mctx, version) + "-java"
+	useFrozenImports := !i.isCurrentVersion(mctx, version)
 	if i.hasVersion() && version == "" {
 		version = i.latestVersion()
 	}
@@ -975,27 +1481,41 @@ func addJavaLibrary(mctx android.LoadHookContext, i *aidlInterface, version stri
 		return ""
 	}
 
-	sdkVersion := proptools.StringDefault(i.properties.Backend.Java.Sdk_version, "system_current")
+	platformApis := proptools.Bool(i.properties.Backend.Java.Platform_apis)
+	var sdkVersion *string
+	if !platformApis {
+		sdkVersion = proptools.StringPtr(proptools.StringDefault(i.properties.Backend.Java.Sdk_version, "system_current"))
+	}
+
+	apexAvailable := i.properties.Apex_available
+	if len(i.properties.Backend.Java.Apex_available) > 0 {
+		apexAvailable = i.properties.Backend.Java.Apex_available
+	}
 
 	mctx.CreateModule(aidlGenFactory, &nameProperties{
 		Name: proptools.StringPtr(javaSourceGen),
 	}, &aidlGenProperties{
-		Srcs:      srcs,
-		AidlRoot:  aidlRoot,
-		Imports:   concat(i.properties.Imports, []string{i.ModuleBase.Name()}),
-		Stability: i.properties.Stability,
-		Lang:      langJava,
-		BaseName:  i.ModuleBase.Name(),
-		Version:   version,
+		Srcs:               srcs,
+		AidlRoot:           aidlRoot,
+		Imports:            concat(i.properties.Imports, []string{i.ModuleBase.Name()}),
+		Headers:            i.properties.Headers,
+		Stability:          i.properties.Stability,
+		Lang:               langJava,
+		BaseName:           i.ModuleBase.Name(),
+		Version:            version,
+		Use_frozen_imports: useFrozenImports,
 	})
 
 	mctx.CreateModule(java.LibraryFactory, &javaProperties{
-		Name:        proptools.StringPtr(javaModuleGen),
-		Installable: proptools.BoolPtr(true),
-		Defaults:    []string{"aidl-java-module-defaults"},
-		Sdk_version: proptools.StringPtr(sdkVersion),
-		Static_libs: wrap("", i.properties.Imports, "-java"),
-		Srcs:        []string{":" + javaSourceGen},
+		Name:            proptools.StringPtr(javaModuleGen),
+		Installable:     proptools.BoolPtr(true),
+		Defaults:        []string{"aidl-java-module-defaults"},
+		Sdk_version:     sdkVersion,
+		Platform_apis:   proptools.BoolPtr(platformApis),
+		Static_libs:     wrap("", i.properties.Imports, "-java"),
+		Srcs:            []string{":" + javaSourceGen},
+		Apex_available:  apexAvailable,
+		Min_sdk_version: i.properties.Min_sdk_version,
 	})
 
 	return javaModuleGen
@@ -1009,11 +1529,35 @@ func addApiModule(mctx android.LoadHookContext, i *aidlInterface) string {
 		BaseName: i.ModuleBase.Name(),
 		Srcs:     i.properties.Srcs,
 		Imports:  concat(i.properties.Imports, []string{i.ModuleBase.Name()}),
+		Headers:  i.properties.Headers,
 		Versions: i.properties.Versions,
+		Frozen:   i.properties.Frozen,
 	})
 	return apiModule
 }
 
+// addMapping auto-generates the aidl_mapping module that every aidl_interface
+// gets for free: a symbol->.aidl-location index (dumped by
+// aidlDumpMappingsRule) for tools that need to translate stack traces or
+// SELinux denials back to interface method names.
+func addMapping(mctx android.LoadHookContext, i *aidlInterface) string {
+	mappingModule := i.ModuleBase.Name() + "-mappings"
+	if mctx.OtherModuleExists(mappingModule) {
+		// aidl_mapping used to be a module type that users hand-wrote in
+		// their Android.bp files. Don't clobber a pre-existing hand-written
+		// module of that name with this auto-generated one.
+		return mappingModule
+	}
+	mctx.CreateModule(aidlMappingFactory, &nameProperties{
+		Name: proptools.StringPtr(mappingModule),
+	}, &aidlMappingProperties{
+		Srcs:    i.properties.Srcs,
+		Output:  i.ModuleBase.Name() + ".mappings",
+		Imports: concat(i.properties.Imports, []string{i.ModuleBase.Name()}),
+	})
+	return mappingModule
+}
+
 func (i *aidlInterface) Name() string {
 	return i.ModuleBase.Name() + aidlInterfaceSuffix
 }
@@ -1051,6 +1595,11 @@ type aidlMappingProperties struct {
 	// Source file of this prebuilt.
 	Srcs   []string `android:"path"`
 	Output string
+
+	// List of aidl_interface modules that this mapping's Srcs import from.
+	// Only set on the aidl_mapping module that aidlInterfaceHook
+	// auto-generates for every aidl_interface.
+	Imports []string
 }
 
 type aidlMapping struct {
@@ -1060,11 +1609,18 @@ type aidlMapping struct {
 }
 
 func (s *aidlMapping) DepsMutator(ctx android.BottomUpMutatorContext) {
+	ctx.AddDependency(ctx.Module(), nil, wrap("", s.properties.Imports, aidlInterfaceSuffix)...)
 }
 
 func (s *aidlMapping) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	srcs, imports := getPaths(ctx, s.properties.Srcs)
 
+	ctx.VisitDirectDeps(func(dep android.Module) {
+		if importedAidl, ok := dep.(*aidlInterface); ok {
+			imports = append(imports, importedAidl.properties.Full_import_paths...)
+		}
+	})
+
 	s.outputFilePath = android.PathForModuleOut(ctx, s.properties.Output)
 	outDir := android.PathForModuleGen(ctx)
 	ctx.Build(pctx, android.BuildParams{
@@ -1100,12 +1656,37 @@ func (m *aidlMapping) AndroidMk() android.AndroidMkData {
 	}
 }
 
+type aidlMappingsSingleton struct{}
+
+func aidlMappingsSingletonFactory() android.Singleton {
+	return &aidlMappingsSingleton{}
+}
+
+// GenerateBuildActions aggregates every aidl_mapping module's output into a
+// single "aidl-mappings" phony target, so `m aidl-mappings` builds the
+// mapping index for every aidl_interface in the tree without callers having
+// to enumerate the per-interface "<iface>-mappings" targets themselves.
+func (s *aidlMappingsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var mappings android.Paths
+	ctx.VisitAllModules(func(module android.Module) {
+		if am, ok := module.(*aidlMapping); ok && am.outputFilePath != nil {
+			mappings = append(mappings, am.outputFilePath)
+		}
+	})
+	ctx.Phony("aidl-mappings", mappings...)
+}
+
 func allAidlInterfacesMakeVars(ctx android.MakeVarsContext) {
 	names := []string{}
+	mappings := []string{}
 	ctx.VisitAllModules(func(module android.Module) {
 		if ai, ok := module.(*aidlInterface); ok {
 			names = append(names, ai.Name())
 		}
+		if am, ok := module.(*aidlMapping); ok && am.outputFilePath != nil {
+			mappings = append(mappings, am.outputFilePath.String())
+		}
 	})
 	ctx.Strict("ALL_AIDL_INTERFACES", strings.Join(names, " "))
+	ctx.Strict("ALL_AIDL_INTERFACES_MAPPINGS", strings.Join(mappings, " "))
 }
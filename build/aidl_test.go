@@ -15,6 +15,7 @@
 package aidl
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -22,10 +23,13 @@ import (
 	"testing"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
+	"android/soong/apex"
 	"android/soong/cc"
 	"android/soong/java"
+	"android/soong/rust"
 )
 
 var buildDir string
@@ -63,6 +67,21 @@ func withFiles(files map[string][]byte) testCustomizer {
 	}
 }
 
+func withAllowMissingDependencies() testCustomizer {
+	return func(fs map[string][]byte, config android.Config) {
+		config.TestProductVariables.Allow_missing_dependencies = proptools.BoolPtr(true)
+	}
+}
+
+// withBpFile adds an extra Android.bp at path to the mock filesystem, so that
+// a module can be parsed with a ModuleDir other than the test root (e.g. to
+// exercise stability's hardware/interfaces restriction).
+func withBpFile(path, content string) testCustomizer {
+	return func(fs map[string][]byte, config android.Config) {
+		fs[path] = []byte(content)
+	}
+}
+
 func _testAidl(t *testing.T, bp string, customizers ...testCustomizer) (*android.TestContext, android.Config) {
 	t.Helper()
 
@@ -183,6 +202,12 @@ func _testAidl(t *testing.T, bp string, customizers ...testCustomizer) (*android
 	ctx.RegisterModuleType("ndk_prebuilt_object", cc.NdkPrebuiltObjectFactory)
 	ctx.RegisterModuleType("ndk_prebuilt_shared_stl", cc.NdkPrebuiltSharedStlFactory)
 	ctx.RegisterModuleType("ndk_prebuilt_static_stl", cc.NdkPrebuiltStaticStlFactory)
+	ctx.RegisterModuleType("rust_library", rust.LibraryFactory)
+	ctx.RegisterModuleType("aidl_rust_source_provider", aidlRustSourceProviderFactory)
+	ctx.RegisterModuleType("aidl_interface_headers", aidlInterfaceHeadersFactory)
+	ctx.RegisterModuleType("aidl_mapping", aidlMappingFactory)
+	ctx.RegisterModuleType("apex", apex.BundleFactory)
+	ctx.RegisterModuleType("apex_key", apex.ApexKeyFactory)
 
 	ctx.PreArchMutators(android.RegisterDefaultsPreArchMutators)
 	ctx.PostDepsMutators(android.RegisterOverridePostDepsMutators)
@@ -192,16 +217,24 @@ func _testAidl(t *testing.T, bp string, customizers ...testCustomizer) (*android
 	return ctx, config
 }
 
-func testAidl(t *testing.T, bp string, customizers ...testCustomizer) (*android.TestContext, android.Config) {
+// testAidlParsing is like testAidl but parses filesToParse instead of just
+// the test root's Android.bp, so a module defined via withBpFile can be
+// picked up from its own ModuleDir.
+func testAidlParsing(t *testing.T, filesToParse []string, bp string, customizers ...testCustomizer) (*android.TestContext, android.Config) {
 	t.Helper()
 	ctx, config := _testAidl(t, bp, customizers...)
-	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	_, errs := ctx.ParseFileList(".", filesToParse)
 	android.FailIfErrored(t, errs)
 	_, errs = ctx.PrepareBuildActions(config)
 	android.FailIfErrored(t, errs)
 	return ctx, config
 }
 
+func testAidl(t *testing.T, bp string, customizers ...testCustomizer) (*android.TestContext, android.Config) {
+	t.Helper()
+	return testAidlParsing(t, []string{"Android.bp"}, bp, customizers...)
+}
+
 func testAidlError(t *testing.T, pattern, bp string, customizers ...testCustomizer) {
 	t.Helper()
 	ctx, config := _testAidl(t, bp, customizers...)
@@ -244,10 +277,15 @@ func TestCreatesModulesWithNoVersions(t *testing.T) {
 			srcs: [
 				"IFoo.aidl",
 			],
+			backend: {
+				rust: {
+					enabled: true,
+				},
+			},
 		}
 	`)
 
-	assertModulesExists(t, ctx, "foo-java", "foo-cpp", "foo-ndk", "foo-ndk_platform")
+	assertModulesExists(t, ctx, "foo-java", "foo-cpp", "foo-ndk", "foo-ndk_platform", "foo-rust")
 }
 
 func TestCreatesModulesWithFrozenVersions(t *testing.T) {
@@ -273,20 +311,414 @@ func TestCreatesModulesWithFrozenVersions(t *testing.T) {
 			versions: [
 				"1",
 			],
+			backend: {
+				rust: {
+					enabled: true,
+				},
+			},
 		}
 	`, withFiles(map[string][]byte{
 		"aidl_api/foo/1/foo.1.aidl": nil,
 	}))
 
 	// For alias for the latest frozen version (=1)
-	assertModulesExists(t, ctx, "foo-java", "foo-cpp", "foo-ndk", "foo-ndk_platform")
+	assertModulesExists(t, ctx, "foo-java", "foo-cpp", "foo-ndk", "foo-ndk_platform", "foo-rust")
 
 	// For frozen version "1"
 	// Note that it is not yet implemented to generate native modules for latest frozen version
 	assertModulesExists(t, ctx, "foo-V1-java")
 
 	// For ToT (current)
-	assertModulesExists(t, ctx, "foo-unstable-java", "foo-unstable-cpp", "foo-unstable-ndk", "foo-unstable-ndk_platform")
+	assertModulesExists(t, ctx, "foo-unstable-java", "foo-unstable-cpp", "foo-unstable-ndk", "foo-unstable-ndk_platform", "foo-unstable-rust")
+}
+
+func TestFrozenVersionsWithMissingApiDirAllowed(t *testing.T) {
+	// Without AllowMissingDependencies, a missing aidl_api/foo/1 directory is a hard error.
+	testAidlError(t, `aidl_api/foo/1`, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+			],
+			versions: [
+				"1",
+			],
+		}
+	`)
+
+	// With AllowMissingDependencies (e.g. unbundled / single-module builds), the same
+	// missing directory should not fail analysis.
+	testAidl(t, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+			],
+			versions: [
+				"1",
+			],
+		}
+	`, withAllowMissingDependencies())
+}
+
+func TestFrozenExplicitlyChecksExactEquality(t *testing.T) {
+	ctx, _ := testAidl(t, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+			],
+			versions: [
+				"1",
+			],
+			frozen: true,
+		}
+	`, withFiles(map[string][]byte{
+		"aidl_api/foo/1/foo.1.aidl": nil,
+		"aidl_api/foo/1/.hash":      nil,
+	}))
+
+	// frozen: true must reject any change to ToT, not just a
+	// backwards-compatible one, so it has to check for exact equality against
+	// the latest frozen version rather than mere compatibility.
+	ctx.ModuleForTests("foo"+aidlApiSuffix, androidVariant).Rule("aidlDiffApiRule")
+}
+
+func TestExplicitlyUnfrozenAlwaysChecksCompatibility(t *testing.T) {
+	ctx, _ := testAidl(t, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+			],
+			versions: [
+				"1",
+			],
+			frozen: false,
+		}
+	`, withFiles(map[string][]byte{
+		"aidl_api/foo/1/foo.1.aidl": nil,
+		"aidl_api/foo/1/.hash":      nil,
+	}))
+
+	// frozen: false means ToT may evolve in a backwards-compatible way
+	// regardless of DefaultAppTargetSdkInt, so it must only ever check
+	// compatibility, never exact equality.
+	ctx.ModuleForTests("foo"+aidlApiSuffix, androidVariant).Rule("aidlCheckApiRule")
+}
+
+func TestFreezeApiAndUpdateApiPhonyTargets(t *testing.T) {
+	ctx, _ := testAidl(t, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+			],
+		}
+		aidl_interface {
+			name: "bar",
+			srcs: [
+				"IBar.aidl",
+			],
+			versions: [
+				"1",
+			],
+		}
+		aidl_interface {
+			name: "baz",
+			srcs: [
+				"IBaz.aidl",
+			],
+			versions: [
+				"1",
+			],
+			frozen: true,
+		}
+	`, withFiles(map[string][]byte{
+		"IBar.aidl":                 nil,
+		"IBaz.aidl":                 nil,
+		"aidl_api/bar/1/bar.1.aidl": nil,
+		"aidl_api/bar/1/.hash":      nil,
+		"aidl_api/baz/1/baz.1.aidl": nil,
+		"aidl_api/baz/1/.hash":      nil,
+	}))
+
+	androidMkFor := func(name string) string {
+		t.Helper()
+		m := ctx.ModuleForTests(name+aidlApiSuffix, androidVariant).Module().(*aidlApi)
+		buf := &bytes.Buffer{}
+		m.AndroidMk().Custom(buf, name+aidlApiSuffix, "", "", android.AndroidMkData{})
+		return buf.String()
+	}
+
+	// foo has no frozen versions yet: update-api has nothing to update.
+	if mk := androidMkFor("foo"); !strings.Contains(mk, "has no frozen versions to update") {
+		t.Errorf("expected foo-update-api to error about no frozen versions, got:\n%s", mk)
+	} else if strings.Contains(mk, "foo-freeze-api:\n\t$(error") {
+		t.Errorf("expected foo-freeze-api to succeed (not frozen), got:\n%s", mk)
+	}
+
+	// bar has a frozen version and isn't explicitly frozen: both targets succeed.
+	if mk := androidMkFor("bar"); strings.Contains(mk, "$(error") {
+		t.Errorf("expected bar-freeze-api and bar-update-api to both succeed, got:\n%s", mk)
+	}
+
+	// baz is explicitly frozen: freeze-api must refuse to freeze a new version.
+	if mk := androidMkFor("baz"); !strings.Contains(mk, "baz is explicitly frozen") {
+		t.Errorf("expected baz-freeze-api to error because baz is explicitly frozen, got:\n%s", mk)
+	}
+}
+
+func TestPlatformApisAllowsVintfStability(t *testing.T) {
+	ctx, _ := testAidlParsing(t, []string{"Android.bp", "hardware/interfaces/Android.bp"}, "",
+		withBpFile("hardware/interfaces/Android.bp", `
+			aidl_interface {
+				name: "foo",
+				srcs: [
+					"IFoo.aidl",
+				],
+				stability: "vintf",
+				backend: {
+					java: {
+						platform_apis: true,
+					},
+				},
+			}
+		`),
+		withFiles(map[string][]byte{
+			"hardware/interfaces/IFoo.aidl": nil,
+		}),
+	)
+
+	assertModulesExists(t, ctx, "foo-java")
+}
+
+func TestFrozenRustImportsResolveAgainstImportCurrentDump(t *testing.T) {
+	ctx, _ := testAidl(t, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+			],
+			imports: [
+				"bar",
+			],
+			versions: [
+				"1",
+			],
+			backend: {
+				rust: {
+					enabled: true,
+				},
+			},
+		}
+		aidl_interface {
+			name: "bar",
+			local_include_dir: "bar_src",
+			srcs: [
+				"bar_src/IBar.aidl",
+			],
+			backend: {
+				rust: {
+					enabled: true,
+				},
+			},
+		}
+	`, withFiles(map[string][]byte{
+		"aidl_api/foo/1/foo.1.aidl": nil,
+		"bar_src/IBar.aidl":         nil,
+	}))
+
+	// "foo-rust" is the alias for the latest frozen version (1), so it must be
+	// built against bar's "current" API dump, not bar's live sources, even
+	// though bar's ToT hasn't diverged from that dump in this test.
+	frozenRule := ctx.ModuleForTests("foo-rust-source", nativeVariant).Rule("aidlRustRule")
+	if strings.Contains(frozenRule.Args["imports"], "-Ibar_src") {
+		t.Errorf("expected frozen foo-rust-source to not import bar's live sources, got %q", frozenRule.Args["imports"])
+	}
+	if !strings.Contains(frozenRule.Args["imports"], filepath.Join(buildDir, ".intermediates", "bar"+aidlApiSuffix)) {
+		t.Errorf("expected frozen foo-rust-source to import bar's current API dump, got %q", frozenRule.Args["imports"])
+	}
+
+	// "foo-unstable-rust-source" is ToT and should keep resolving imports
+	// against bar's live sources.
+	totRule := ctx.ModuleForTests("foo-unstable-rust-source", nativeVariant).Rule("aidlRustRule")
+	if !strings.Contains(totRule.Args["imports"], "-Ibar_src") {
+		t.Errorf("expected ToT foo-unstable-rust-source to import bar's live sources, got %q", totRule.Args["imports"])
+	}
+}
+
+func TestRustSourceProviderColocatesGeneratedSubmodulesWithLibRs(t *testing.T) {
+	ctx, _ := testAidl(t, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+				"sub/IBar.aidl",
+			],
+			backend: {
+				rust: {
+					enabled: true,
+				},
+			},
+		}
+	`, withFiles(map[string][]byte{
+		"sub/IBar.aidl": nil,
+	}))
+
+	// A multi-file interface makes aidl --lang=rust emit lib.rs plus
+	// submodule .rs files (mirroring the "sub" package) into outDir. rustc
+	// resolves lib.rs's `mod` declarations relative to lib.rs's own
+	// directory, so the declared output must actually live inside outDir,
+	// not be copied out to some other directory.
+	rule := ctx.ModuleForTests("foo-rust-source", nativeVariant).Rule("aidlRustRule")
+	outDir := rule.Args["outDir"]
+	libRsDir := filepath.Dir(rule.Output.String())
+	if libRsDir != outDir {
+		t.Errorf("expected lib.rs to be generated inside outDir (%q) so sibling submodule "+
+			".rs files are reachable from it, but lib.rs is at %q", outDir, libRsDir)
+	}
+}
+
+func TestApexAvailableIsForwardedToBackends(t *testing.T) {
+	ctx, _ := testAidl(t, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+			],
+			apex_available: [
+				"com.android.foo",
+			],
+			min_sdk_version: "29",
+			backend: {
+				rust: {
+					enabled: true,
+				},
+			},
+		}
+	`)
+
+	ndkModule, ok := ctx.ModuleForTests("foo-ndk", nativeVariant).Module().(android.ApexModule)
+	if !ok {
+		t.Fatalf("foo-ndk is not an android.ApexModule")
+	}
+	if !ndkModule.AvailableFor("com.android.foo") {
+		t.Errorf("expected foo-ndk to be available for com.android.foo")
+	}
+
+	rustModule, ok := ctx.ModuleForTests("foo-rust", nativeVariant).Module().(android.ApexModule)
+	if !ok {
+		t.Fatalf("foo-rust is not an android.ApexModule")
+	}
+	if !rustModule.AvailableFor("com.android.foo") {
+		t.Errorf("expected foo-rust to be available for com.android.foo")
+	}
+}
+
+func withNativeBridgeTarget() testCustomizer {
+	return func(fs map[string][]byte, config android.Config) {
+		config.Targets[android.Android] = append(config.Targets[android.Android], android.Target{
+			Os: android.Android,
+			Arch: android.Arch{
+				ArchType:    android.Arm,
+				ArchVariant: "armv7-a-neon",
+				Abi:         []string{"armeabi-v7a"},
+			},
+			NativeBridge:             android.NativeBridgeEnabled,
+			NativeBridgeHostArchName: "x86",
+			NativeBridgeRelativePath: "arm",
+		})
+	}
+}
+
+func TestNativeBridgeVariant(t *testing.T) {
+	ctx, _ := testAidl(t, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+			],
+			backend: {
+				cpp: {
+					native_bridge_supported: true,
+				},
+			},
+		}
+	`, withNativeBridgeTarget())
+
+	nativeBridgeVariant := "android_arm_armv7-a-neon_native_bridge_shared"
+	producer, ok := ctx.ModuleForTests("foo-cpp", nativeBridgeVariant).Module().(android.OutputFileProducer)
+	if !ok {
+		t.Fatalf("foo-cpp(%s): should be OutputFileProducer.", nativeBridgeVariant)
+	}
+	paths, err := producer.OutputFiles("")
+	if err != nil {
+		t.Fatalf("foo-cpp(%s): failed to get OutputFiles: %v", nativeBridgeVariant, err)
+	}
+	if len(paths) != 1 || paths[0].Base() != "foo-V1-cpp.so" {
+		t.Errorf("foo-cpp(%s): expected output %q, but got %v", nativeBridgeVariant, "foo-V1-cpp.so", paths)
+	}
+}
+
+func TestAidlInterfaceHeaders(t *testing.T) {
+	ctx, _ := testAidl(t, `
+		aidl_interface_headers {
+			name: "foo-headers",
+			srcs: [
+				"IFoo.aidl",
+			],
+			local_include_dir: ".",
+		}
+		aidl_interface {
+			name: "bar",
+			srcs: [
+				"IBar.aidl",
+			],
+			headers: [
+				"foo-headers",
+			],
+		}
+	`, withFiles(map[string][]byte{
+		"IBar.aidl": nil,
+	}))
+
+	assertModulesExists(t, ctx, "foo-headers", "bar-cpp")
+}
+
+func TestHandWrittenAidlMappingIsNotClobbered(t *testing.T) {
+	// aidl_mapping used to be a module type that users hand-wrote; the
+	// auto-generated "<name>-mappings" module must not collide with one.
+	ctx, _ := testAidl(t, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+			],
+		}
+		aidl_mapping {
+			name: "foo-mappings",
+			srcs: [
+				"foo.mappings"
+			],
+		}
+	`, withFiles(map[string][]byte{
+		"foo.mappings": nil,
+	}))
+
+	assertModulesExists(t, ctx, "foo-mappings")
+}
+
+func TestAidlInterfaceHeadersRejectsEscapingIncludeDir(t *testing.T) {
+	testAidlError(t, `must be relative path`, `
+		aidl_interface_headers {
+			name: "foo-headers",
+			srcs: [
+				"IFoo.aidl",
+			],
+			local_include_dir: "../../../",
+		}
+	`)
 }
 
 const (
@@ -450,6 +882,31 @@ func TestImports(t *testing.T) {
 		"IBar.aidl": nil,
 	}))
 
+	testAidlError(t, `backend.rust.enabled: Rust backend not enabled in the imported AIDL interface "bar"`, `
+		aidl_interface {
+			name: "foo",
+			srcs: [
+				"IFoo.aidl",
+			],
+			imports: [
+				"bar",
+			],
+			backend: {
+				rust: {
+					enabled: true,
+				},
+			},
+		}
+		aidl_interface {
+			name: "bar",
+			srcs: [
+				"IBar.aidl",
+			],
+		}
+	`, withFiles(map[string][]byte{
+		"IBar.aidl": nil,
+	}))
+
 	ctx, _ := testAidl(t, `
 		aidl_interface {
 			name: "foo",
@@ -458,13 +915,23 @@ func TestImports(t *testing.T) {
 			],
 			imports: [
 				"bar",
-			]
+			],
+			backend: {
+				rust: {
+					enabled: true,
+				},
+			},
 		}
 		aidl_interface {
 			name: "bar",
 			srcs: [
 				"IBar.aidl",
 			],
+			backend: {
+				rust: {
+					enabled: true,
+				},
+			},
 		}
 	`, withFiles(map[string][]byte{
 		"IBar.aidl": nil,
@@ -476,4 +943,6 @@ func TestImports(t *testing.T) {
 	if !strings.Contains(libFlags, libBar) {
 		t.Errorf("%q is not found in %q", libBar, libFlags)
 	}
+
+	assertModulesExists(t, ctx, "foo-rust", "bar-rust")
 }